@@ -2,11 +2,14 @@ package function
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/pubsub"
@@ -17,12 +20,14 @@ import (
 var channelTopic string
 var projectID string
 var collection string
+var feedCacheCollection string
 
 func init() {
 	functions.HTTP("ListChannels", listChannels)
 	channelTopic = os.Getenv("CHANNEL_TOPIC")
 	projectID = os.Getenv("PROJECT_ID")
 	collection = os.Getenv("COLLECTION")
+	feedCacheCollection = os.Getenv("FEED_CACHE_COLLECTION")
 }
 
 func listChannels(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +51,15 @@ func listChannels(w http.ResponseWriter, r *http.Request) {
 		}
 
 		channel := doc.Data()
+		if enabled, ok := channel["enabled"].(bool); ok && !enabled {
+			continue
+		}
+
+		feedURL, _ := channel["url"].(string)
+		if feedURL != "" && !dueForPolling(ctx, client, feedURL) {
+			continue
+		}
+
 		err = publishChannel(ctx, channel)
 		if err != nil {
 			log.Printf("publish channel error: %v", err)
@@ -55,6 +69,24 @@ func listChannels(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Success")
 }
 
+// dueForPolling reports whether feedURL's feed_cache entry allows polling
+// now, i.e. it has no nextPollAfter or nextPollAfter has already passed.
+func dueForPolling(ctx context.Context, client *firestore.Client, feedURL string) bool {
+	sum := sha256.Sum256([]byte(feedURL))
+	doc, err := client.Collection(feedCacheCollection).Doc(hex.EncodeToString(sum[:])).Get(ctx)
+	if err != nil {
+		// No cache entry yet, or a transient read error: err on the side of polling.
+		return true
+	}
+
+	nextPollAfter, ok := doc.Data()["nextPollAfter"].(time.Time)
+	if !ok {
+		return true
+	}
+
+	return time.Now().UTC().After(nextPollAfter)
+}
+
 func publishChannel(ctx context.Context, channel map[string]interface{}) error {
 	client, err := pubsub.NewClient(ctx, projectID)
 	if err != nil {
@@ -0,0 +1,299 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var projectID string
+var collection string
+var router *mux.Router
+var authClient *auth.Client
+
+func init() {
+	functions.HTTP("ManageChannels", ManageChannels)
+	projectID = os.Getenv("PROJECT_ID")
+	collection = os.Getenv("COLLECTION")
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID})
+	if err != nil {
+		log.Fatalf("firebase.NewApp: %v", err)
+	}
+	authClient, err = app.Auth(ctx)
+	if err != nil {
+		log.Fatalf("firebase auth client error: %v", err)
+	}
+
+	router = mux.NewRouter()
+	router.HandleFunc("/channels", createChannel).Methods(http.MethodPost)
+	router.HandleFunc("/channels", listOwnedChannels).Methods(http.MethodGet)
+	router.HandleFunc("/channels/{id}", getChannel).Methods(http.MethodGet)
+	router.HandleFunc("/channels/{id}", updateChannel).Methods(http.MethodPut)
+	router.HandleFunc("/channels/{id}", deleteChannel).Methods(http.MethodDelete)
+}
+
+// ManageChannels is the HTTP entrypoint registered with the functions framework.
+// It authenticates the caller against Firebase Auth and dispatches to the
+// channel CRUD routes.
+func ManageChannels(w http.ResponseWriter, r *http.Request) {
+	router.ServeHTTP(w, r)
+}
+
+// Channel is the Firestore representation of a subscribed feed, as exposed
+// over the management API.
+type Channel struct {
+	ID        string            `json:"id,omitempty" firestore:"-"`
+	Name      string            `json:"name" firestore:"name"`
+	FeedURL   string            `json:"url" firestore:"url"`
+	NotifyURL string            `json:"notify" firestore:"notify"`
+	Notifier  string            `json:"notifier" firestore:"notifier"`
+	Options   map[string]string `json:"options" firestore:"options"`
+	Filters   Filters           `json:"filters" firestore:"filters"`
+	Owner     string            `json:"owner" firestore:"owner"`
+	Enabled   bool              `json:"enabled" firestore:"enabled"`
+	CreatedAt time.Time         `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Filters is the per-channel include/exclude configuration consumed by the
+// feed processor; it's just carried through untouched here.
+type Filters struct {
+	Include FilterRule `json:"include" firestore:"include"`
+	Exclude FilterRule `json:"exclude" firestore:"exclude"`
+}
+
+// FilterRule matches an item by title, content, categories, or authors.
+type FilterRule struct {
+	TitleRegex   []string `json:"title_regex" firestore:"title_regex"`
+	ContentRegex []string `json:"content_regex" firestore:"content_regex"`
+	Categories   []string `json:"categories" firestore:"categories"`
+	Authors      []string `json:"authors" firestore:"authors"`
+}
+
+// authenticate verifies the `Authorization: Bearer <idToken>` header against
+// Firebase Auth and returns the caller's uid.
+func authenticate(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", fmt.Errorf("missing bearer token")
+	}
+	idToken := strings.TrimPrefix(header, "Bearer ")
+
+	token, err := authClient.VerifyIDToken(r.Context(), idToken)
+	if err != nil {
+		return "", fmt.Errorf("VerifyIDToken: %v", err)
+	}
+	return token.UID, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func createChannel(w http.ResponseWriter, r *http.Request) {
+	uid, err := authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var channel Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	channel.ID = uuid.NewString()
+	channel.Owner = uid
+	channel.Enabled = true
+	channel.CreatedAt = now
+	channel.UpdatedAt = now
+
+	ctx := r.Context()
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("firestore client error: %v", err))
+		return
+	}
+	defer client.Close()
+
+	if _, err := client.Collection(collection).Doc(channel.ID).Set(ctx, channel); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed writing channel: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, channel)
+}
+
+func listOwnedChannels(w http.ResponseWriter, r *http.Request) {
+	uid, err := authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx := r.Context()
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("firestore client error: %v", err))
+		return
+	}
+	defer client.Close()
+
+	channels := make([]Channel, 0)
+	iter := client.Collection(collection).Where("owner", "==", uid).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err != nil {
+			break
+		}
+		var channel Channel
+		if err := doc.DataTo(&channel); err != nil {
+			log.Printf("failed decoding channel %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		channel.ID = doc.Ref.ID
+		channels = append(channels, channel)
+	}
+
+	writeJSON(w, http.StatusOK, channels)
+}
+
+func loadOwnedChannel(ctx context.Context, client *firestore.Client, uid, id string) (*Channel, int, error) {
+	doc, err := client.Collection(collection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("channel not found: %v", err)
+	}
+
+	var channel Channel
+	if err := doc.DataTo(&channel); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed decoding channel: %v", err)
+	}
+	channel.ID = doc.Ref.ID
+
+	if channel.Owner != uid {
+		return nil, http.StatusForbidden, fmt.Errorf("not the owner of this channel")
+	}
+
+	return &channel, http.StatusOK, nil
+}
+
+func getChannel(w http.ResponseWriter, r *http.Request) {
+	uid, err := authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("firestore client error: %v", err))
+		return
+	}
+	defer client.Close()
+
+	channel, status, err := loadOwnedChannel(ctx, client, uid, id)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func updateChannel(w http.ResponseWriter, r *http.Request) {
+	uid, err := authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("firestore client error: %v", err))
+		return
+	}
+	defer client.Close()
+
+	existing, status, err := loadOwnedChannel(ctx, client, uid, id)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	var update Channel
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	update.ID = existing.ID
+	update.Owner = existing.Owner
+	update.Enabled = existing.Enabled
+	update.CreatedAt = existing.CreatedAt
+	update.UpdatedAt = time.Now().UTC()
+
+	if _, err := client.Collection(collection).Doc(id).Set(ctx, update); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed updating channel: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, update)
+}
+
+func deleteChannel(w http.ResponseWriter, r *http.Request) {
+	uid, err := authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	ctx := r.Context()
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("firestore client error: %v", err))
+		return
+	}
+	defer client.Close()
+
+	if _, status, err := loadOwnedChannel(ctx, client, uid, id); err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	if _, err := client.Collection(collection).Doc(id).Delete(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed deleting channel: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,156 @@
+package function
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/mmcdole/gofeed"
+)
+
+// FeedCache records the conditional-GET validators and polling schedule for
+// a single feed URL, so processChannel can skip re-downloading and
+// re-parsing feeds that haven't changed.
+type FeedCache struct {
+	ETag          string    `firestore:"etag"`
+	LastModified  string    `firestore:"lastModified"`
+	LastPolled    time.Time `firestore:"lastPolled"`
+	NextPollAfter time.Time `firestore:"nextPollAfter"`
+}
+
+// feedCacheID returns the feed_cache document ID for a feed URL: a sha256
+// hash, since URLs can contain characters Firestore document IDs disallow.
+func feedCacheID(feedURL string) string {
+	sum := sha256.Sum256([]byte(feedURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadFeedCache(ctx context.Context, client *firestore.Client, feedURL string) (FeedCache, error) {
+	doc, err := client.Collection(feedCacheCollection).Doc(feedCacheID(feedURL)).Get(ctx)
+	if err != nil {
+		if doc == nil || !doc.Exists() {
+			return FeedCache{}, nil
+		}
+		return FeedCache{}, fmt.Errorf("failed reading feed cache: %v", err)
+	}
+
+	var cache FeedCache
+	if err := doc.DataTo(&cache); err != nil {
+		return FeedCache{}, fmt.Errorf("failed decoding feed cache: %v", err)
+	}
+	return cache, nil
+}
+
+func saveFeedCache(ctx context.Context, client *firestore.Client, feedURL string, cache FeedCache) error {
+	_, err := client.Collection(feedCacheCollection).Doc(feedCacheID(feedURL)).Set(ctx, cache, firestore.MergeAll)
+	if err != nil {
+		return fmt.Errorf("failed writing feed cache: %v", err)
+	}
+	return nil
+}
+
+// saveFilterStats records how many items a channel's filters matched vs.
+// dropped on the most recent poll, so users can tune their rules. It's
+// stored alongside the feed's cache entry since both are keyed by feed URL.
+func saveFilterStats(ctx context.Context, client *firestore.Client, feedURL string, stats filterStats) error {
+	_, err := client.Collection(feedCacheCollection).Doc(feedCacheID(feedURL)).Set(ctx, map[string]interface{}{
+		"matched":     stats.Matched,
+		"filteredOut": stats.FilteredOut,
+	}, firestore.MergeAll)
+	if err != nil {
+		return fmt.Errorf("failed writing filter stats: %v", err)
+	}
+	return nil
+}
+
+// nextPollAfter derives when the feed should next be polled from the
+// response's Retry-After or Cache-Control: max-age headers, whichever is
+// present. It returns the zero time if neither header is set.
+func nextPollAfter(resp *http.Response, now time.Time) time.Time {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+		if when, err := http.ParseTime(retryAfter); err == nil {
+			return when
+		}
+	}
+
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	return time.Time{}
+}
+
+// fetchFeed conditionally fetches feedURL using the cached ETag / Last-Modified
+// validators for that URL. It returns a nil feed (and no error) when the
+// server responds 304 Not Modified, signaling the caller that there's
+// nothing new to process.
+func fetchFeed(ctx context.Context, client *firestore.Client, feedURL string) (*gofeed.Feed, error) {
+	cache, err := loadFeedCache(ctx, client, feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed building request: %v", err)
+	}
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching feed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	now := time.Now().UTC()
+
+	if resp.StatusCode == http.StatusNotModified {
+		cache.LastPolled = now
+		if next := nextPollAfter(resp, now); !next.IsZero() {
+			cache.NextPollAfter = next
+		}
+		if err := saveFeedCache(ctx, client, feedURL, cache); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching feed: %s", resp.Status)
+	}
+
+	feed, err := gofeed.NewParser().Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing feed: %v", err)
+	}
+
+	cache.ETag = resp.Header.Get("ETag")
+	cache.LastModified = resp.Header.Get("Last-Modified")
+	cache.LastPolled = now
+	cache.NextPollAfter = nextPollAfter(resp, now)
+	if err := saveFeedCache(ctx, client, feedURL, cache); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
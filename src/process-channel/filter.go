@@ -0,0 +1,149 @@
+package function
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Filters is the per-channel include/exclude configuration applied to a
+// feed's items before they're checked against the dedup store.
+type Filters struct {
+	Include FilterRule `json:"include"`
+	Exclude FilterRule `json:"exclude"`
+}
+
+// FilterRule matches an item by title, content, categories, or authors. A
+// rule with no fields set matches nothing.
+type FilterRule struct {
+	TitleRegex   []string `json:"title_regex"`
+	ContentRegex []string `json:"content_regex"`
+	Categories   []string `json:"categories"`
+	Authors      []string `json:"authors"`
+}
+
+type compiledFilterRule struct {
+	titleRegex   []*regexp.Regexp
+	contentRegex []*regexp.Regexp
+	categories   map[string]bool
+	authors      map[string]bool
+}
+
+func compileFilterRule(rule FilterRule) (compiledFilterRule, error) {
+	compiled := compiledFilterRule{
+		categories: toSet(rule.Categories),
+		authors:    toSet(rule.Authors),
+	}
+
+	for _, pattern := range rule.TitleRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledFilterRule{}, fmt.Errorf("invalid title_regex %q: %v", pattern, err)
+		}
+		compiled.titleRegex = append(compiled.titleRegex, re)
+	}
+
+	for _, pattern := range rule.ContentRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledFilterRule{}, fmt.Errorf("invalid content_regex %q: %v", pattern, err)
+		}
+		compiled.contentRegex = append(compiled.contentRegex, re)
+	}
+
+	return compiled, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func (c compiledFilterRule) empty() bool {
+	return len(c.titleRegex) == 0 && len(c.contentRegex) == 0 && len(c.categories) == 0 && len(c.authors) == 0
+}
+
+func (c compiledFilterRule) matches(item *gofeed.Item) bool {
+	for _, re := range c.titleRegex {
+		if re.MatchString(item.Title) {
+			return true
+		}
+	}
+	for _, re := range c.contentRegex {
+		if re.MatchString(item.Content) {
+			return true
+		}
+	}
+	for _, category := range item.Categories {
+		if c.categories[category] {
+			return true
+		}
+	}
+	for _, author := range itemAuthors(item) {
+		if c.authors[author] {
+			return true
+		}
+	}
+	return false
+}
+
+func itemAuthors(item *gofeed.Item) []string {
+	authors := make([]string, 0, len(item.Authors))
+	for _, author := range item.Authors {
+		if author == nil {
+			continue
+		}
+		if author.Email != "" {
+			authors = append(authors, author.Email)
+			continue
+		}
+		authors = append(authors, author.Name)
+	}
+	return authors
+}
+
+// filterStats summarizes the outcome of applyFilters for a single
+// processChannel invocation.
+type filterStats struct {
+	Matched     int `firestore:"matched"`
+	FilteredOut int `firestore:"filteredOut"`
+}
+
+// applyFilters compiles the channel's include/exclude rules once and
+// returns the items that pass them. An item must match at least one
+// include rule (when any are configured) and must match no exclude rule.
+func applyFilters(items []*gofeed.Item, filters Filters) ([]*gofeed.Item, filterStats, error) {
+	include, err := compileFilterRule(filters.Include)
+	if err != nil {
+		return nil, filterStats{}, err
+	}
+	exclude, err := compileFilterRule(filters.Exclude)
+	if err != nil {
+		return nil, filterStats{}, err
+	}
+
+	if include.empty() && exclude.empty() {
+		return items, filterStats{Matched: len(items)}, nil
+	}
+
+	var stats filterStats
+	kept := make([]*gofeed.Item, 0, len(items))
+	for _, item := range items {
+		if !include.empty() && !include.matches(item) {
+			stats.FilteredOut++
+			continue
+		}
+		if exclude.matches(item) {
+			stats.FilteredOut++
+			continue
+		}
+		stats.Matched++
+		kept = append(kept, item)
+	}
+
+	return kept, stats, nil
+}
@@ -9,21 +9,29 @@ import (
 	"sync/atomic"
 
 	"cloud.google.com/go/firestore"
-	"cloud.google.com/go/pubsub"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+	cepubsub "github.com/cloudevents/sdk-go/protocol/pubsub/v2"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/google/uuid"
 	"github.com/mmcdole/gofeed"
 )
 
+// itemEventType is the CloudEvents `type` attribute set on every item
+// published to itemTopic.
+const itemEventType = "com.github.chankh.gcp-rss-notifier.item.new"
+
 var itemTopic string
 var projectID string
 var collection string
+var feedCacheCollection string
 
 func init() {
 	functions.CloudEvent("ProcessChannel", processChannel)
 	itemTopic = os.Getenv("ITEM_TOPIC")
 	projectID = os.Getenv("PROJECT_ID")
 	collection = os.Getenv("COLLECTION")
+	feedCacheCollection = os.Getenv("FEED_CACHE_COLLECTION")
 }
 
 // MessagePublishedData contains the full Pub/Sub message
@@ -41,17 +49,22 @@ type PubSubMessage struct {
 }
 
 type ChannelConfig struct {
-	FeedURL   string `json:"url"`
-	NotifyURL string `json:"notify"`
+	FeedURL   string            `json:"url"`
+	NotifyURL string            `json:"notify"`
+	Notifier  string            `json:"notifier"`
+	Options   map[string]string `json:"options"`
+	Filters   Filters           `json:"filters"`
 }
 
 type FeedItem struct {
-	NotifyURL string `json:"notify"`
-	ID        string `json:"id"`
-	Updated   string `json:"updated"`
-	Link      string `json:"link"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
+	NotifyURL string            `json:"notify"`
+	Notifier  string            `json:"notifier"`
+	Options   map[string]string `json:"options"`
+	ID        string            `json:"id"`
+	Updated   string            `json:"updated"`
+	Link      string            `json:"link"`
+	Title     string            `json:"title"`
+	Content   string            `json:"content"`
 }
 
 func processChannel(ctx context.Context, e event.Event) error {
@@ -66,64 +79,91 @@ func processChannel(ctx context.Context, e event.Event) error {
 		return fmt.Errorf("failed parsing JSON: %v", err)
 	}
 
-	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(channelConfig.FeedURL)
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("firestore client error: %v", err)
+	}
+	defer client.Close()
+
+	feed, err := fetchFeed(ctx, client, channelConfig.FeedURL)
+	if err != nil {
+		return fmt.Errorf("failed fetching feed: %v", err)
+	}
+	if feed == nil {
+		// 304 Not Modified: nothing new to process.
+		return nil
+	}
+
+	filtered, stats, err := applyFilters(feed.Items, channelConfig.Filters)
 	if err != nil {
-		return fmt.Errorf("failed parsing feed: %v", err)
+		return fmt.Errorf("invalid filters: %v", err)
+	}
+	if err := saveFilterStats(ctx, client, channelConfig.FeedURL, stats); err != nil {
+		return err
 	}
 
-	items, err := removeOldItems(ctx, feed.Items)
+	items, err := removeOldItems(ctx, filtered)
 	if err != nil {
 		return fmt.Errorf("failed processing items: %v", err)
 	}
-	publishItem(ctx, channelConfig.NotifyURL, items)
+	publishItem(ctx, channelConfig.FeedURL, channelConfig.NotifyURL, channelConfig.Notifier, channelConfig.Options, items)
 
 	return nil
 }
 
-func publishItem(ctx context.Context, notifyURL string, items []*gofeed.Item) error {
-	client, err := pubsub.NewClient(ctx, projectID)
+// publishItem emits each item as a CloudEvent over the CloudEvents Pub/Sub
+// protocol binding, so ce-* attributes (id, source, subject, time) ride
+// along as native Pub/Sub message attributes instead of being buried in an
+// opaque JSON payload.
+func publishItem(ctx context.Context, feedURL, notifyURL, notifierName string, notifierOptions map[string]string, items []*gofeed.Item) error {
+	p, err := cepubsub.New(ctx, cepubsub.WithProjectID(projectID), cepubsub.WithTopicID(itemTopic), cepubsub.WithOrderingKey(feedURL))
 	if err != nil {
-		return fmt.Errorf("pubsub.NewClient: %v", err)
+		return fmt.Errorf("failed creating pubsub protocol: %v", err)
+	}
+	defer p.Close(ctx)
+
+	client, err := cloudevents.NewClient(p)
+	if err != nil {
+		return fmt.Errorf("failed creating cloudevents client: %v", err)
 	}
-	defer client.Close()
 
 	var wg sync.WaitGroup
 	var totalErrors uint64
-	t := client.Topic(itemTopic)
 
 	for _, item := range items {
 		feed := FeedItem{
 			NotifyURL: notifyURL,
+			Notifier:  notifierName,
+			Options:   notifierOptions,
 			ID:        item.GUID,
 			Title:     item.Title,
 			Content:   item.Content,
 			Link:      item.Link,
 			Updated:   item.Updated,
 		}
-		itemJson, err := json.Marshal(feed)
-		if err != nil {
-			return fmt.Errorf("json.Marshal: %v", err)
-		}
 
-		result := t.Publish(ctx, &pubsub.Message{
-			Data: []byte(itemJson),
-		})
+		ce := cloudevents.NewEvent()
+		ce.SetID(uuid.NewString())
+		ce.SetType(itemEventType)
+		ce.SetSource(feedURL)
+		ce.SetSubject(item.GUID)
+		if item.UpdatedParsed != nil {
+			ce.SetTime(*item.UpdatedParsed)
+		}
+		if err := ce.SetData(cloudevents.ApplicationJSON, feed); err != nil {
+			return fmt.Errorf("failed setting cloudevent data: %v", err)
+		}
 
 		wg.Add(1)
-		go func(res *pubsub.PublishResult) {
+		go func(ce event.Event) {
 			defer wg.Done()
-			// The Get method blocks until a server-generated ID or
-			// an error is returned for the published message.
-			id, err := res.Get(ctx)
-			if err != nil {
-				// Error handling code can be added here.
-				fmt.Printf("Failed to publish: %v\n", err)
+			if result := client.Send(ctx, ce); cloudevents.IsUndelivered(result) {
+				fmt.Printf("Failed to publish: %v\n", result)
 				atomic.AddUint64(&totalErrors, 1)
 				return
 			}
-			fmt.Printf("Published message to topic %s; msg ID: %v\n", itemTopic, id)
-		}(result)
+			fmt.Printf("Published cloudevent to topic %s; id: %s\n", itemTopic, ce.ID())
+		}(ce)
 	}
 
 	wg.Wait()
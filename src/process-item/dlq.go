@@ -0,0 +1,45 @@
+package function
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"cloud.google.com/go/pubsub"
+)
+
+var dlqTopic string
+
+func init() {
+	dlqTopic = os.Getenv("DLQ_TOPIC")
+}
+
+// publishToDLQ republishes a failed item's event data to the
+// configured dead-letter topic, tagged with the error that exhausted
+// retries, instead of returning an error that would make Pub/Sub redeliver
+// the message forever.
+func publishToDLQ(ctx context.Context, data []byte, cause error) error {
+	if dlqTopic == "" {
+		return fmt.Errorf("no DLQ_TOPIC configured for failed delivery: %v", cause)
+	}
+
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("pubsub.NewClient: %v", err)
+	}
+	defer client.Close()
+
+	t := client.Topic(dlqTopic)
+	result := t.Publish(ctx, &pubsub.Message{
+		Data: data,
+		Attributes: map[string]string{
+			"error": cause.Error(),
+		},
+	})
+
+	if _, err := result.Get(ctx); err != nil {
+		return fmt.Errorf("failed publishing to dlq: %v", err)
+	}
+
+	return nil
+}
@@ -1,19 +1,14 @@
 package function
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
-	md "github.com/JohannesKaufmann/html-to-markdown"
-	"github.com/PuerkitoBio/goquery"
 	"github.com/cloudevents/sdk-go/v2/event"
 )
 
@@ -35,13 +30,15 @@ type PubSubMessage struct {
 }
 
 type FeedItem struct {
-	NotifyURL string `json:"notify"`
-	Feed      string `json:"feed"`
-	ID        string `json:"id"`
-	Updated   string `json:"updated"`
-	Link      string `json:"link"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
+	NotifyURL string            `json:"notify"`
+	Notifier  string            `json:"notifier"`
+	Options   map[string]string `json:"options"`
+	Feed      string            `json:"feed"`
+	ID        string            `json:"id"`
+	Updated   string            `json:"updated"`
+	Link      string            `json:"link"`
+	Title     string            `json:"title"`
+	Content   string            `json:"content"`
 }
 
 func init() {
@@ -51,133 +48,107 @@ func init() {
 }
 
 func processItem(ctx context.Context, e event.Event) error {
-	var msg MessagePublishedData
-	if err := e.DataAs(&msg); err != nil {
-		return fmt.Errorf("event.DataAs: %v", err)
-	}
-
-	item := FeedItem{}
-	err := json.Unmarshal(msg.Message.Data, &item)
+	item, err := decodeFeedItem(e)
 	if err != nil {
 		return fmt.Errorf("failed parsing JSON: %v", err)
 	}
 
-	err = notify(item)
+	// item.ID is the feed item's stable GUID, the same key removeOldItems
+	// uses in process-channel, so it doubles as the idempotency key here.
+	deliveryID := item.ID
+
+	notified, err := alreadyNotified(ctx, deliveryID)
 	if err != nil {
-		return fmt.Errorf("failed sending notification: %v", err)
+		return fmt.Errorf("failed checking idempotency: %v", err)
+	}
+	if notified {
+		fmt.Printf("item %s already notified, skipping\n", deliveryID)
+		return nil
 	}
 
-	err = save(ctx, item)
-	if err != nil {
+	if err := notify(item); err != nil {
+		if dlqErr := publishToDLQ(ctx, e.Data(), err); dlqErr != nil {
+			return fmt.Errorf("failed sending notification: %v, and failed sending to dlq: %v", err, dlqErr)
+		}
+		fmt.Printf("item %s failed delivery after retries, sent to dlq: %v\n", deliveryID, err)
+		return nil
+	}
+
+	if err := save(ctx, deliveryID, item); err != nil {
 		return fmt.Errorf("failed updating record: %v", err)
 	}
 	return nil
 }
 
-func notify(item FeedItem) error {
-	text, err := htmlToMarkdown(item.Content)
-	if err != nil {
-		return fmt.Errorf("failed converting to markdown: %v", err)
+// decodeFeedItem accepts either a CloudEvent emitted directly for a feed
+// item, or the legacy Eventarc MessagePublishedData envelope wrapping a
+// JSON-encoded FeedItem, kept for backward compatibility during rollout.
+func decodeFeedItem(e event.Event) (FeedItem, error) {
+	var legacy MessagePublishedData
+	if err := e.DataAs(&legacy); err == nil && len(legacy.Message.Data) > 0 {
+		var item FeedItem
+		if err := json.Unmarshal(legacy.Message.Data, &item); err != nil {
+			return FeedItem{}, fmt.Errorf("failed parsing legacy message data: %v", err)
+		}
+		return item, nil
 	}
 
-	// Add title and link before contents
-	text = fmt.Sprintf("%s <%s|%s>\n\n%s", item.Feed, item.Link, item.Title, text)
-
-	// Trim text if more than 4000 chars
-	if len(text) > 4000 {
-		text = text[:4000]
+	var item FeedItem
+	if err := e.DataAs(&item); err != nil {
+		return FeedItem{}, fmt.Errorf("event.DataAs: %v", err)
 	}
+	return item, nil
+}
 
-	msg := make(map[string]string)
-	msg["text"] = text
-	jsonBody, err := json.Marshal(msg)
+func notify(item FeedItem) error {
+	notifier, err := newNotifier(item.Notifier)
 	if err != nil {
-		return fmt.Errorf("json.Marshal: %v", err)
+		return fmt.Errorf("failed resolving notifier: %v", err)
 	}
 
-	resp, err := http.Post(item.NotifyURL, "application/json", bytes.NewReader(jsonBody))
+	return notifier.Notify(item)
+}
+
+// alreadyNotified reports whether deliveryID has already been delivered, so
+// processItem can skip re-notifying under Pub/Sub's at-least-once delivery.
+func alreadyNotified(ctx context.Context, deliveryID string) (bool, error) {
+	client, err := firestore.NewClient(ctx, projectID)
 	if err != nil {
-		return fmt.Errorf("error making http request: %v", err)
+		return false, fmt.Errorf("firestore client error: %v", err)
 	}
+	defer client.Close()
 
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("response status: %s, %v", resp.Status, string(b))
+	doc, err := client.Collection(collection).Doc(deliveryID).Get(ctx)
+	if err != nil {
+		if doc == nil || !doc.Exists() {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed reading item: %v", err)
 	}
 
-	return nil
-}
-
-func htmlToMarkdown(html string) (string, error) {
-	opt := &md.Options{
-		StrongDelimiter: "*",
-	}
-	converter := md.NewConverter("", true, opt)
-
-	// Replace <a> rule conversion for Google Chat support
-	converter.AddRules(
-		md.Rule{
-			Filter: []string{"a"},
-			AdvancedReplacement: func(content string, selec *goquery.Selection, opt *md.Options) (md.AdvancedResult, bool) {
-				// if there is no href, no link is used. So just return the content inside the link
-				href, ok := selec.Attr("href")
-				if !ok || strings.TrimSpace(href) == "" || strings.TrimSpace(href) == "#" {
-					return md.AdvancedResult{
-						Markdown: content,
-					}, false
-				}
-
-				// having multiline content inside a link is a bit tricky
-				content = md.EscapeMultiLine(content)
-
-				var title string
-				if t, ok := selec.Attr("title"); ok {
-					t = strings.Replace(t, "\n", " ", -1)
-					// escape all quotes
-					t = strings.Replace(t, `"`, `\"`, -1)
-					title = fmt.Sprintf(` "%s"`, t)
-				}
-
-				// if there is no link content (for example because it contains an svg)
-				// the 'title' or 'aria-label' attribute is used instead.
-				if strings.TrimSpace(content) == "" {
-					content = selec.AttrOr("title", selec.AttrOr("aria-label", ""))
-				}
-
-				// a link without text won't de displayed anyway
-				if content == "" {
-					return md.AdvancedResult{}, true
-				}
-
-				markdown := fmt.Sprintf("<%s%s|%s>", href, title, content)
-				markdown = md.AddSpaceIfNessesary(selec, markdown)
-
-				return md.AdvancedResult{Markdown: markdown}, false
-			},
-		},
-	)
-
-	return converter.ConvertString(html)
+	notifiedAt, ok := doc.Data()["notifiedAt"]
+	return ok && notifiedAt != nil, nil
 }
 
-func save(ctx context.Context, item FeedItem) error {
+func save(ctx context.Context, deliveryID string, item FeedItem) error {
 	client, err := firestore.NewClient(ctx, projectID)
 	if err != nil {
 		return fmt.Errorf("firestore client error: %v", err)
 	}
 	defer client.Close()
 
-	wr, err := client.Collection(collection).Doc(item.ID).Set(ctx, map[string]string{
+	wr, err := client.Collection(collection).Doc(deliveryID).Set(ctx, map[string]interface{}{
 		"id":         item.ID,
 		"lastUpdate": item.Updated,
 		"title":      item.Title,
 		"content":    item.Content,
 		"link":       item.Link,
+		"notifiedAt": time.Now().UTC(),
 	}, firestore.MergeAll)
 	if err != nil {
 		return fmt.Errorf("failed writing to firestore: %v", err)
 	}
 
-	fmt.Printf("record updated, id: %s, timestamp: %s\n", item.ID, wr.UpdateTime)
+	fmt.Printf("record updated, id: %s, timestamp: %s\n", deliveryID, wr.UpdateTime)
 	return nil
 }
@@ -0,0 +1,98 @@
+package function
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Notifier delivers a FeedItem to an external messaging system.
+type Notifier interface {
+	Notify(item FeedItem) error
+}
+
+// newNotifier returns the Notifier for the given channel notifier name.
+// An empty name defaults to Google Chat, the original behavior of this
+// module.
+func newNotifier(name string) (Notifier, error) {
+	switch name {
+	case "", "googlechat":
+		return googleChatNotifier{}, nil
+	case "slack":
+		return slackNotifier{}, nil
+	case "discord":
+		return discordNotifier{}, nil
+	case "msteams":
+		return msTeamsNotifier{}, nil
+	case "generic":
+		return genericNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", name)
+	}
+}
+
+// postJSON POSTs body to url with the given extra headers, on top of
+// Content-Type: application/json, retrying with exponential backoff on
+// 429/5xx responses and honoring Retry-After when the server sends one.
+// Any other non-200 response is treated as permanent and returned
+// immediately.
+func postJSON(url string, body []byte, headers map[string]string) error {
+	operation := func() error {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(fmt.Errorf("error building http request: %v", err))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("error making http request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+
+		b, _ := io.ReadAll(resp.Body)
+		statusErr := fmt.Errorf("response status: %s, %v", resp.Status, string(b))
+
+		// Only 429 and 5xx are considered transient. Anything else — including
+		// a 2xx/3xx that isn't exactly 200 — is treated as a permanent failure:
+		// webhook endpoints for the notifiers above always reply 200 on success,
+		// so a different code means the payload itself was rejected and retrying
+		// unchanged would just repeat the same outcome.
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+			return backoff.Permanent(statusErr)
+		}
+
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			time.Sleep(wait)
+		}
+
+		return statusErr
+	}
+
+	return backoff.Retry(operation, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 5))
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
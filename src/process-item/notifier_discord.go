@@ -0,0 +1,34 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// discordNotifier posts a Discord webhook message containing a single
+// embed with the item title, link, and converted body.
+type discordNotifier struct{}
+
+func (discordNotifier) Notify(item FeedItem) error {
+	text, err := toCommonMarkdown(item.Content)
+	if err != nil {
+		return fmt.Errorf("failed converting to markdown: %v", err)
+	}
+
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       item.Title,
+				"url":         item.Link,
+				"description": text,
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+
+	return postJSON(item.NotifyURL, body, nil)
+}
@@ -0,0 +1,84 @@
+package function
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"text/template"
+)
+
+const signatureHeader = "X-GCP-RSS-Signature"
+
+// genericPayload is the whitelisted set of item fields exposed to generic
+// webhooks, either JSON-encoded directly or as the data for
+// options["body_template"]. It deliberately excludes FeedItem.Options and
+// FeedItem.NotifyURL: those carry the channel's HMAC secret and its own
+// endpoint, neither of which should ever leave this module.
+type genericPayload struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Content string `json:"content"`
+	Updated string `json:"updated"`
+}
+
+// genericNotifier posts a feed item to any webhook as JSON, signing the
+// body with HMAC-SHA256 using the channel's secret so the receiver can
+// verify it actually came from this module. By default the body is the
+// whitelisted item fields; a channel can instead set options["body_template"]
+// to a Go text/template string to shape the body itself, e.g. to match a
+// third-party API's expected schema.
+type genericNotifier struct{}
+
+func (genericNotifier) Notify(item FeedItem) error {
+	payload := genericPayload{
+		ID:      item.ID,
+		Title:   item.Title,
+		Link:    item.Link,
+		Content: item.Content,
+		Updated: item.Updated,
+	}
+
+	var body []byte
+	if tmpl := item.Options["body_template"]; tmpl != "" {
+		rendered, err := renderBodyTemplate(tmpl, payload)
+		if err != nil {
+			return fmt.Errorf("failed rendering body_template: %v", err)
+		}
+		body = rendered
+	} else {
+		marshaled, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %v", err)
+		}
+		body = marshaled
+	}
+
+	headers := map[string]string{}
+	if secret := item.Options["secret"]; secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		headers[signatureHeader] = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	return postJSON(item.NotifyURL, body, headers)
+}
+
+// renderBodyTemplate executes tmpl (a Go text/template string) against the
+// whitelisted item fields in payload, so a channel's body_template can only
+// ever reference data that's already safe to send externally.
+func renderBodyTemplate(tmpl string, payload genericPayload) ([]byte, error) {
+	t, err := template.New("body").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("template.Execute: %v", err)
+	}
+	return buf.Bytes(), nil
+}
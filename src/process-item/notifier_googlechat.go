@@ -0,0 +1,33 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// googleChatNotifier posts a text message built from the original Google
+// Chat webhook payload: `<link|title>` markdown followed by the item body,
+// truncated at 4000 characters.
+type googleChatNotifier struct{}
+
+func (googleChatNotifier) Notify(item FeedItem) error {
+	text, err := toGoogleChatMarkdown(item.Content)
+	if err != nil {
+		return fmt.Errorf("failed converting to markdown: %v", err)
+	}
+
+	// Add title and link before contents
+	text = fmt.Sprintf("%s <%s|%s>\n\n%s", item.Feed, item.Link, item.Title, text)
+
+	// Trim text if more than 4000 chars
+	if len(text) > 4000 {
+		text = text[:4000]
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+
+	return postJSON(item.NotifyURL, body, nil)
+}
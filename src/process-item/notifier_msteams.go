@@ -0,0 +1,49 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const defaultTeamsThemeColor = "0076D7"
+
+// msTeamsNotifier posts a MessageCard to a Microsoft Teams incoming
+// webhook, with a "View" action linking back to the item.
+type msTeamsNotifier struct{}
+
+func (msTeamsNotifier) Notify(item FeedItem) error {
+	text, err := toCommonMarkdown(item.Content)
+	if err != nil {
+		return fmt.Errorf("failed converting to markdown: %v", err)
+	}
+
+	themeColor := item.Options["theme_color"]
+	if themeColor == "" {
+		themeColor = defaultTeamsThemeColor
+	}
+
+	payload := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    item.Title,
+		"themeColor": themeColor,
+		"title":      item.Title,
+		"text":       text,
+		"potentialAction": []map[string]interface{}{
+			{
+				"@type": "OpenUri",
+				"name":  "View",
+				"targets": []map[string]string{
+					{"os": "default", "uri": item.Link},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+
+	return postJSON(item.NotifyURL, body, nil)
+}
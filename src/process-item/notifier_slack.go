@@ -0,0 +1,39 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// slackNotifier posts a Slack incoming-webhook message using block kit, so
+// the item title renders as a link above the converted body.
+type slackNotifier struct{}
+
+func (slackNotifier) Notify(item FeedItem) error {
+	text, err := toCommonMarkdown(item.Content)
+	if err != nil {
+		return fmt.Errorf("failed converting to markdown: %v", err)
+	}
+
+	payload := map[string]interface{}{
+		"blocks": []map[string]interface{}{
+			{
+				"type": "section",
+				"text": map[string]string{
+					"type": "mrkdwn",
+					"text": fmt.Sprintf("*<%s|%s>*\n\n%s", item.Link, item.Title, text),
+				},
+			},
+		},
+	}
+	if channelID := item.Options["channel_id"]; channelID != "" {
+		payload["channel"] = channelID
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %v", err)
+	}
+
+	return postJSON(item.NotifyURL, body, nil)
+}